@@ -0,0 +1,124 @@
+package gphotos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	photoslibrary "google.golang.org/api/photoslibrary/v1"
+)
+
+// ErrDone is returned by AlbumIterator.Next when there are no further
+// albums to return.
+var ErrDone = errors.New("gphotos: no more items in iterator")
+
+// AlbumID identifies an album created with CreateAlbum.
+type AlbumID string
+
+// AlbumPosition controls where newly created media items land within an
+// album when passed to CreateMediaItems via CreateMediaItemsOptions.
+type AlbumPosition int
+
+const (
+	// AlbumPositionUnspecified leaves placement up to the API's default.
+	AlbumPositionUnspecified AlbumPosition = iota
+	AlbumPositionFirst
+	AlbumPositionLast
+)
+
+func (p AlbumPosition) apiValue() string {
+	switch p {
+	case AlbumPositionFirst:
+		return "FIRST_IN_ALBUM"
+	case AlbumPositionLast:
+		return "LAST_IN_ALBUM"
+	default:
+		return ""
+	}
+}
+
+// CreateAlbum creates a new, empty album titled title and returns its ID.
+func (u *Uploader) CreateAlbum(ctx context.Context, title string) (AlbumID, error) {
+	req := &photoslibrary.CreateAlbumRequest{
+		Album: &photoslibrary.Album{Title: title},
+	}
+
+	album, err := u.service.Albums.Create(req).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+
+	return AlbumID(album.Id), nil
+}
+
+// AddMediaItemsToAlbum adds the media items identified by mediaItemIDs
+// to album via albums.batchAddMediaItems. Google allows at most 50 IDs
+// per call; callers adding more than that should split mediaItemIDs into
+// batches themselves. Like CreateMediaItemsWithMetadata, the call is
+// paced and retried using the Uploader's configured Pacer and
+// RetryPolicy so a long-running sync does not blow through the same
+// write quota.
+func (u *Uploader) AddMediaItemsToAlbum(ctx context.Context, album AlbumID, mediaItemIDs []string) error {
+	if len(mediaItemIDs) > MaxUploadTokensPerCreateMediaItemsCall {
+		return fmt.Errorf("too many media item ids, got %v, cannot handle more than %v", len(mediaItemIDs), MaxUploadTokensPerCreateMediaItemsCall)
+	}
+
+	req := &photoslibrary.AlbumBatchAddMediaItemsRequest{
+		MediaItemIds: mediaItemIDs,
+	}
+
+	if err := u.pacer.Wait(ctx); err != nil {
+		return err
+	}
+
+	return Retry(ctx, u.retryPolicy, func() error {
+		_, err := u.service.Albums.BatchAddMediaItems(string(album), req).Context(ctx).Do()
+		return err
+	})
+}
+
+// AlbumIterator pages through a user's albums, fetching additional
+// pages from the Library API as needed.
+type AlbumIterator struct {
+	ctx     context.Context
+	service *photoslibrary.AlbumsService
+
+	albums    []*photoslibrary.Album
+	pageToken string
+	done      bool
+}
+
+// Next returns the next album, or ErrDone once the iterator is exhausted.
+func (it *AlbumIterator) Next() (*photoslibrary.Album, error) {
+	for len(it.albums) == 0 {
+		if it.done {
+			return nil, ErrDone
+		}
+
+		call := it.service.List().Context(it.ctx).PageSize(50)
+		if it.pageToken != "" {
+			call = call.PageToken(it.pageToken)
+		}
+
+		resp, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+
+		it.albums = resp.Albums
+		it.pageToken = resp.NextPageToken
+		if it.pageToken == "" {
+			it.done = true
+		}
+	}
+
+	album := it.albums[0]
+	it.albums = it.albums[1:]
+	return album, nil
+}
+
+// ListAlbums returns an iterator over the albums owned by the
+// authenticated user.
+func (u *Uploader) ListAlbums(ctx context.Context) *AlbumIterator {
+	return &AlbumIterator{ctx: ctx, service: u.service.Albums}
+}
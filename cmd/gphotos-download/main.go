@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alindeman/gphotos"
+	"github.com/alindeman/gphotos/internal/googleauth"
+	"github.com/pkg/errors"
+	photoslibrary "google.golang.org/api/photoslibrary/v1"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], err)
+		os.Exit(255)
+	}
+}
+
+func run() error {
+	ctx := context.Background()
+
+	var credentialsFile, tokenFile, destDir string
+	flag.StringVar(&credentialsFile, "credentials-file", "", "OAuth Client ID configuration file (downloadable from https://console.cloud.google.com/apis/credentials)")
+	flag.StringVar(&tokenFile, "token-file", "", "File to load or store an OAuth token")
+	flag.StringVar(&destDir, "dest", ".", "Directory to write downloaded media items to")
+	flag.Parse()
+
+	if credentialsFile == "" {
+		return errors.New("missing required flag: credentials-file")
+	} else if tokenFile == "" {
+		return errors.New("missing required flag: token-file")
+	}
+
+	oauthClient, err := googleauth.Client(ctx, credentialsFile, tokenFile, photoslibrary.PhotoslibraryScope)
+	if err != nil {
+		return err
+	}
+
+	u := gphotos.NewUploader(oauthClient)
+
+	it := u.ListMediaItems(ctx)
+	for {
+		item, err := it.Next()
+		if err == gphotos.ErrDone {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "failed to list media items")
+		}
+
+		if err := downloadMediaItem(ctx, u, destDir, item); err != nil {
+			return errors.Wrapf(err, "failed to download %q", item.Filename)
+		}
+
+		fmt.Printf("downloaded %q\n", item.Filename)
+	}
+
+	return nil
+}
+
+func downloadMediaItem(ctx context.Context, u *gphotos.Uploader, destDir string, item *photoslibrary.MediaItem) error {
+	dest := uniquePath(filepath.Join(destDir, item.Filename))
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := u.Download(ctx, item, f); err != nil {
+		return err
+	}
+
+	if item.MediaMetadata == nil {
+		return nil
+	}
+
+	creationTime, err := time.Parse(time.RFC3339, item.MediaMetadata.CreationTime)
+	if err != nil {
+		return nil
+	}
+
+	return os.Chtimes(dest, creationTime, creationTime)
+}
+
+// uniquePath appends " (n)" before the extension until it finds a path
+// that does not already exist, so that downloading the same filename
+// twice does not clobber the first copy.
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
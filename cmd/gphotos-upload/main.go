@@ -2,19 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/alindeman/gphotos"
-	retry "github.com/avast/retry-go"
+	"github.com/alindeman/gphotos/internal/googleauth"
 	"github.com/pkg/errors"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"golang.org/x/sync/errgroup"
 	photoslibrary "google.golang.org/api/photoslibrary/v1"
 )
@@ -31,49 +29,86 @@ func run() error {
 
 	var credentialsFile, tokenFile string
 	var uploadConcurrency int
+	var resumableThreshold, chunkSize int64
+	var album string
+	var createAlbum bool
+	var descriptionFromSidecar bool
+	var dedupDB string
+	var fingerprintOnly bool
+	var progressMode string
 	flag.StringVar(&credentialsFile, "credentials-file", "", "OAuth Client ID configuration file (downloadable from https://console.cloud.google.com/apis/credentials)")
 	flag.StringVar(&tokenFile, "token-file", "", "File to load or store an OAuth token")
 	flag.IntVar(&uploadConcurrency, "upload-concurrency", 10, "Number of threads that are uploading files concurrency")
+	flag.Int64Var(&resumableThreshold, "resumable-threshold", 10*1024*1024, "Files at least this many bytes are uploaded using the resumable upload protocol")
+	flag.Int64Var(&chunkSize, "chunk-size", gphotos.DefaultChunkSize, "Chunk size used for resumable uploads, in bytes")
+	flag.StringVar(&album, "album", "", "Add uploaded photos to the named album")
+	flag.BoolVar(&createAlbum, "create-album", false, "Create the -album if it does not already exist")
+	flag.BoolVar(&descriptionFromSidecar, "description-from-sidecar", false, "Set each photo's description from a sidecar .txt file with the same basename, if one exists")
+	flag.StringVar(&dedupDB, "dedup-db", "", "Path to a dedup cache database; files already uploaded according to the cache are skipped")
+	flag.BoolVar(&fingerprintOnly, "fingerprint", false, "Print each file's content hash to stdout instead of uploading")
+	flag.StringVar(&progressMode, "progress", "tty", "How to report upload progress: tty, json, or none")
 	flag.Parse()
 
+	if fingerprintOnly {
+		return printFingerprints(flag.Args())
+	}
+
 	if credentialsFile == "" {
 		return errors.New("missing required flag: credentials-file")
 	} else if tokenFile == "" {
 		return errors.New("missing required flag: token-file")
 	} else if uploadConcurrency <= 0 {
 		return errors.New("upload-concurrency must be greater than 0")
+	} else if createAlbum && album == "" {
+		return errors.New("create-album requires -album")
 	}
 
-	credentialJSON, err := ioutil.ReadFile(credentialsFile)
+	progress, err := newProgress(progressMode)
 	if err != nil {
-		return errors.Wrap(err, "failed to read credentials")
+		return err
 	}
 
-	oauthConfig, err := google.ConfigFromJSON(credentialJSON, photoslibrary.PhotoslibraryScope)
+	oauthClient, err := googleauth.Client(ctx, credentialsFile, tokenFile, photoslibrary.PhotoslibraryScope)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse credentials")
+		return err
 	}
 
-	token, err := readTokenFromFile(tokenFile)
-	if os.IsNotExist(err) {
-		token, err = fetchToken(ctx, oauthConfig)
+	u := gphotos.NewUploader(oauthClient, gphotos.WithProgress(progress))
+	u.ChunkSize = chunkSize
+
+	// fileUploader performs the actual file transfer inside the retry
+	// loop below. It reports OnFileBytes as usual but not
+	// OnFileStart/OnFileDone: the retry loop calls Upload/UploadResumable
+	// once per attempt, and firing those events from inside the library
+	// call would over-count a file that needed more than one attempt.
+	// uploadOneFile reports them itself, once per file, around the loop.
+	var fileProgress gphotos.Progress
+	if progress != nil {
+		fileProgress = bytesOnlyProgress{progress}
+	}
+	fileUploader := gphotos.NewUploader(oauthClient, gphotos.WithProgress(fileProgress))
+	fileUploader.ChunkSize = chunkSize
+
+	var albumID gphotos.AlbumID
+	if album != "" {
+		albumID, err = resolveAlbum(ctx, u, album, createAlbum)
 		if err != nil {
-			return errors.Wrap(err, "failed to fetch token")
+			return errors.Wrap(err, "failed to resolve album")
 		}
-	} else if err != nil {
-		return errors.Wrap(err, "failed to load token from file")
 	}
+	createOpts := gphotos.CreateMediaItemsOptions{AlbumID: albumID}
+	retryPolicy := u.RetryPolicy()
 
-	if err := saveTokenToFile(tokenFile, token); err != nil {
-		return errors.Wrap(err, "failed to save token to file")
+	var deduper *gphotos.Deduper
+	if dedupDB != "" {
+		deduper, err = gphotos.NewDeduper(dedupDB)
+		if err != nil {
+			return errors.Wrap(err, "failed to open dedup-db")
+		}
+		defer deduper.Close()
 	}
 
-	oauthClient := oauthConfig.Client(ctx, token)
-	u := gphotos.NewUploader(oauthClient)
-
 	g, ctx := errgroup.WithContext(ctx)
-	messages := make(chan string)
-	defer close(messages)
 
 	// 1 thread to shove filenames into filenames channel
 	filenames := make(chan string)
@@ -92,7 +127,8 @@ func run() error {
 	})
 
 	// N threads uploading files
-	uploadTokens := make(chan gphotos.UploadToken, gphotos.MaxUploadTokensPerCreateMediaItemsCall)
+	mediaItems := make(chan uploadResult, gphotos.MaxUploadTokensPerCreateMediaItemsCall)
+	dedupAlbumAdds := make(chan string, gphotos.MaxUploadTokensPerCreateMediaItemsCall)
 	var wg sync.WaitGroup
 	for i := 0; i < uploadConcurrency; i++ {
 		wg.Add(1)
@@ -101,24 +137,38 @@ func run() error {
 			defer wg.Done()
 
 			for filename := range filenames {
-				var uploadToken gphotos.UploadToken
-				err := retry.Do(func() error {
+				var hash string
+				if deduper != nil {
 					var err error
-					uploadToken, err = uploadFile(u, filename)
-					return err
-				})
-				if err != nil {
-					return errors.Wrapf(err, "error uploading %q", filename)
+					hash, err = fingerprintFile(filename)
+					if err != nil {
+						return errors.Wrapf(err, "error fingerprinting %q", filename)
+					}
+
+					mediaItemID, found, err := deduper.Lookup(hash)
+					if err != nil {
+						return errors.Wrapf(err, "error checking dedup cache for %q", filename)
+					}
+					if found {
+						if albumID != "" {
+							select {
+							case dedupAlbumAdds <- mediaItemID:
+							case <-ctx.Done():
+								return ctx.Err()
+							}
+						}
+						notifySkip(progress, filename)
+						continue
+					}
 				}
 
-				select {
-				case uploadTokens <- uploadToken:
-				case <-ctx.Done():
-					return ctx.Err()
+				result, err := uploadOneFile(ctx, fileUploader, progress, retryPolicy, filename, resumableThreshold, descriptionFromSidecar, hash)
+				if err != nil {
+					return errors.Wrapf(err, "error uploading %q", filename)
 				}
 
 				select {
-				case messages <- fmt.Sprintf("uploaded %q", filename):
+				case mediaItems <- result:
 				case <-ctx.Done():
 					return ctx.Err()
 				}
@@ -129,39 +179,49 @@ func run() error {
 	}
 	go func() {
 		wg.Wait()
-		close(uploadTokens)
+		close(mediaItems)
+		close(dedupAlbumAdds)
 	}()
 
 	// 1 thread creating media items in batches
 	g.Go(func() error {
-		uploadBatch := func(batch []gphotos.UploadToken) error {
+		uploadBatch := func(batch []uploadResult) error {
 			if len(batch) == 0 {
 				return nil
 			}
 
-			err := retry.Do(func() error {
-				return u.CreateMediaItems(batch)
-			})
+			items := make([]gphotos.MediaItem, len(batch))
+			for i, result := range batch {
+				items[i] = result.item
+			}
+
+			results, err := u.CreateMediaItemsWithMetadata(ctx, items, createOpts)
 			if err != nil {
 				return err
 			}
 
-			select {
-			case messages <- fmt.Sprintf("uploaded batch of %v photos", len(batch)):
-			case <-ctx.Done():
-				return ctx.Err()
+			if deduper != nil {
+				for i, result := range results {
+					if i >= len(batch) || batch[i].hash == "" || result.MediaItem == nil {
+						continue
+					}
+					if err := deduper.Record(batch[i].hash, result.MediaItem.Id); err != nil {
+						return err
+					}
+				}
 			}
+
 			return nil
 		}
 
-		currentBatch := []gphotos.UploadToken{}
-		for uploadToken := range uploadTokens {
-			currentBatch = append(currentBatch, uploadToken)
+		currentBatch := []uploadResult{}
+		for result := range mediaItems {
+			currentBatch = append(currentBatch, result)
 			if len(currentBatch) >= gphotos.MaxUploadTokensPerCreateMediaItemsCall {
 				if err := uploadBatch(currentBatch); err != nil {
 					return err
 				}
-				currentBatch = []gphotos.UploadToken{}
+				currentBatch = []uploadResult{}
 			}
 		}
 
@@ -169,11 +229,29 @@ func run() error {
 		return uploadBatch(currentBatch)
 	})
 
-	go func() {
-		for message := range messages {
-			fmt.Printf("%v\n", message)
+	// 1 thread adding dedup-cache hits to the album in batches
+	g.Go(func() error {
+		addBatch := func(batch []string) error {
+			if len(batch) == 0 {
+				return nil
+			}
+			return u.AddMediaItemsToAlbum(ctx, albumID, batch)
 		}
-	}()
+
+		currentBatch := []string{}
+		for mediaItemID := range dedupAlbumAdds {
+			currentBatch = append(currentBatch, mediaItemID)
+			if len(currentBatch) >= gphotos.MaxUploadTokensPerCreateMediaItemsCall {
+				if err := addBatch(currentBatch); err != nil {
+					return err
+				}
+				currentBatch = []string{}
+			}
+		}
+
+		// Add final batch, if any
+		return addBatch(currentBatch)
+	})
 
 	if err := g.Wait(); err != nil {
 		return errors.Wrap(err, "failed to upload photos")
@@ -181,51 +259,185 @@ func run() error {
 	return nil
 }
 
-func readTokenFromFile(tokenFile string) (*oauth2.Token, error) {
-	token := new(oauth2.Token)
+// uploadResult pairs the MediaItem produced by uploadFile with the
+// content hash it was uploaded under, if dedup is enabled, so the
+// batch-creation stage can record it in the Deduper once the item's ID
+// is known.
+type uploadResult struct {
+	item gphotos.MediaItem
+	hash string
+}
 
-	f, err := os.Open(tokenFile)
-	if err != nil {
-		return nil, err
+// uploadOneFile reports OnFileStart/OnFileDone once for filename, then
+// retries uploadFile against u according to retryPolicy. Reporting
+// start/done here, around the whole retry loop, rather than inside
+// uploadFile's library calls, keeps each file counted exactly once in
+// progress even if it takes more than one attempt to land.
+func uploadOneFile(ctx context.Context, u *gphotos.Uploader, progress gphotos.Progress, retryPolicy gphotos.RetryPolicy, filename string, resumableThreshold int64, descriptionFromSidecar bool, hash string) (uploadResult, error) {
+	var size int64 = -1
+	if info, err := os.Stat(filename); err == nil {
+		size = info.Size()
 	}
-	defer f.Close()
+	if progress != nil {
+		progress.OnFileStart(filename, size)
+	}
+
+	var result uploadResult
+	err := gphotos.Retry(ctx, retryPolicy, func() error {
+		var err error
+		result, err = uploadFile(ctx, u, filename, resumableThreshold, descriptionFromSidecar, hash)
+		return err
+	})
 
-	if err := json.NewDecoder(f).Decode(token); err != nil {
-		return nil, err
+	if progress != nil {
+		progress.OnFileDone(filename, result.item.Token, err)
 	}
+	return result, err
+}
 
-	return token, err
+// bytesOnlyProgress forwards every Progress event to the embedded sink
+// except OnFileStart/OnFileDone, which it swallows. It wraps the
+// progress sink used by the Uploader that performs the retried file
+// transfer in uploadOneFile, so the real per-file start/done events
+// come from uploadOneFile itself rather than from every individual
+// retry attempt.
+type bytesOnlyProgress struct {
+	gphotos.Progress
 }
 
-func fetchToken(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
-	url := config.AuthCodeURL("state", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following URL, then paste the authorization token: %v\n\n", url)
-	fmt.Printf("Auth code: ")
+func (bytesOnlyProgress) OnFileStart(filename string, size int64)                          {}
+func (bytesOnlyProgress) OnFileDone(filename string, token gphotos.UploadToken, err error) {}
 
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		return nil, err
+func uploadFile(ctx context.Context, u *gphotos.Uploader, filename string, resumableThreshold int64, descriptionFromSidecar bool, hash string) (uploadResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return uploadResult{}, err
 	}
+	defer f.Close()
 
-	return config.Exchange(ctx, authCode)
-}
+	info, err := f.Stat()
+	if err != nil {
+		return uploadResult{}, err
+	}
 
-func saveTokenToFile(tokenFile string, token *oauth2.Token) error {
-	f, err := os.OpenFile(tokenFile, os.O_RDWR|os.O_CREATE, 0600)
+	var token gphotos.UploadToken
+	if info.Size() >= resumableThreshold {
+		token, err = u.UploadResumable(ctx, filepath.Base(filename), f, info.Size())
+	} else {
+		token, err = u.Upload(ctx, filepath.Base(filename), f)
+	}
 	if err != nil {
-		return err
+		return uploadResult{}, err
+	}
+
+	var description string
+	if descriptionFromSidecar {
+		description, err = sidecarDescription(filename)
+		if err != nil {
+			return uploadResult{}, err
+		}
 	}
-	defer f.Close()
 
-	return json.NewEncoder(f).Encode(token)
+	return uploadResult{item: gphotos.MediaItem{Token: token, Description: description}, hash: hash}, nil
 }
 
-func uploadFile(u *gphotos.Uploader, filename string) (gphotos.UploadToken, error) {
+// fingerprintFile returns the hex-encoded SHA-256 hash of filename's
+// content.
+func fingerprintFile(filename string) (string, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
 
-	return u.Upload(filepath.Base(filename), f)
+	return gphotos.Fingerprint(f)
+}
+
+// newProgress constructs the Progress sink named by mode: "tty" renders a
+// single status line to stderr, "json" writes JSON-lines events to
+// stdout, and "none" discards events (nil, which WithProgress treats as
+// a no-op).
+func newProgress(mode string) (gphotos.Progress, error) {
+	switch mode {
+	case "tty":
+		return gphotos.NewTTYProgress(os.Stderr), nil
+	case "json":
+		return gphotos.NewJSONProgress(os.Stdout), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown -progress mode %q (want tty, json, or none)", mode)
+	}
+}
+
+// notifySkip reports a dedup-skipped file through progress as an
+// instantaneous, already-done file so tty/json sinks still account for
+// it in their totals.
+func notifySkip(progress gphotos.Progress, filename string) {
+	if progress == nil {
+		return
+	}
+	progress.OnFileStart(filename, 0)
+	progress.OnFileDone(filename, "", nil)
+}
+
+// printFingerprints writes each filename's content hash to stdout
+// without uploading anything.
+func printFingerprints(filenames []string) error {
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+
+		hash, err := gphotos.Fingerprint(f)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to fingerprint %q", filename)
+		}
+
+		fmt.Printf("%s  %s\n", hash, filename)
+	}
+
+	return nil
+}
+
+// sidecarDescription reads the description for filename from a sidecar
+// .txt file with the same basename (e.g. IMG_0001.jpg -> IMG_0001.txt),
+// if one exists.
+func sidecarDescription(filename string) (string, error) {
+	sidecar := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".txt"
+
+	data, err := ioutil.ReadFile(sidecar)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveAlbum finds the album titled albumTitle, creating it if
+// createAlbum is set and no such album exists.
+func resolveAlbum(ctx context.Context, u *gphotos.Uploader, albumTitle string, createAlbum bool) (gphotos.AlbumID, error) {
+	it := u.ListAlbums(ctx)
+	for {
+		album, err := it.Next()
+		if err == gphotos.ErrDone {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		if album.Title == albumTitle {
+			return gphotos.AlbumID(album.Id), nil
+		}
+	}
+
+	if !createAlbum {
+		return "", fmt.Errorf("album %q does not exist (use -create-album to create it)", albumTitle)
+	}
+
+	return u.CreateAlbum(ctx, albumTitle)
 }
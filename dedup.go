@@ -0,0 +1,105 @@
+package gphotos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var dedupBucket = []byte("media_items")
+
+// Deduper avoids re-uploading files that have already been pushed to
+// Google Photos in a previous run by keeping a persistent, on-disk
+// cache of content hash -> media item ID.
+type Deduper struct {
+	db *bolt.DB
+}
+
+type dedupEntry struct {
+	MediaItemID string    `json:"media_item_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewDeduper opens (creating if necessary) a dedup cache at path.
+// Callers should Close it when done.
+func NewDeduper(path string) (*Deduper, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dedupBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Deduper{db: db}, nil
+}
+
+// Close releases the underlying database file.
+func (d *Deduper) Close() error {
+	return d.db.Close()
+}
+
+// Fingerprint computes the hex-encoded SHA-256 hash of r's content. It
+// is exposed separately from Lookup/Record so callers can implement a
+// -fingerprint style mode that reports hashes without consulting or
+// updating the cache.
+func Fingerprint(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Lookup returns the media item ID previously recorded for hash, and
+// whether one was found.
+func (d *Deduper) Lookup(hash string) (string, bool, error) {
+	var mediaItemID string
+	var found bool
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(dedupBucket).Get([]byte(hash))
+		if v == nil {
+			return nil
+		}
+
+		var entry dedupEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+
+		mediaItemID = entry.MediaItemID
+		found = true
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return mediaItemID, found, nil
+}
+
+// Record stores hash -> mediaItemID so that a future upload of the same
+// content can be skipped.
+func (d *Deduper) Record(hash, mediaItemID string) error {
+	entry := dedupEntry{MediaItemID: mediaItemID, CreatedAt: time.Now()}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(dedupBucket).Put([]byte(hash), data)
+	})
+}
@@ -0,0 +1,87 @@
+// Package googleauth contains the OAuth2 plumbing shared by gphotos's
+// command-line tools.
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Client loads the OAuth client configuration from credentialsFile and
+// returns an authenticated *http.Client for scope. It reuses the token
+// cached in tokenFile, or performs the interactive OAuth flow and
+// caches the result there if tokenFile does not yet exist.
+func Client(ctx context.Context, credentialsFile, tokenFile, scope string) (*http.Client, error) {
+	credentialJSON, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read credentials")
+	}
+
+	oauthConfig, err := google.ConfigFromJSON(credentialJSON, scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse credentials")
+	}
+
+	token, err := readTokenFromFile(tokenFile)
+	if os.IsNotExist(err) {
+		token, err = fetchToken(ctx, oauthConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch token")
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to load token from file")
+	}
+
+	if err := saveTokenToFile(tokenFile, token); err != nil {
+		return nil, errors.Wrap(err, "failed to save token to file")
+	}
+
+	return oauthConfig.Client(ctx, token), nil
+}
+
+func readTokenFromFile(tokenFile string) (*oauth2.Token, error) {
+	token := new(oauth2.Token)
+
+	f, err := os.Open(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(token); err != nil {
+		return nil, err
+	}
+
+	return token, err
+}
+
+func fetchToken(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	url := config.AuthCodeURL("state", oauth2.AccessTypeOffline)
+	fmt.Printf("Go to the following URL, then paste the authorization token: %v\n\n", url)
+	fmt.Printf("Auth code: ")
+
+	var authCode string
+	if _, err := fmt.Scan(&authCode); err != nil {
+		return nil, err
+	}
+
+	return config.Exchange(ctx, authCode)
+}
+
+func saveTokenToFile(tokenFile string, token *oauth2.Token) error {
+	f, err := os.OpenFile(tokenFile, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(token)
+}
@@ -0,0 +1,158 @@
+package gphotos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	photoslibrary "google.golang.org/api/photoslibrary/v1"
+)
+
+// MediaItemIterator pages through a sequence of media items, fetching
+// additional pages on demand.
+type MediaItemIterator struct {
+	ctx   context.Context
+	fetch func(pageToken string) (items []*photoslibrary.MediaItem, nextPageToken string, err error)
+
+	items     []*photoslibrary.MediaItem
+	pageToken string
+	done      bool
+}
+
+// Next returns the next media item, or ErrDone once the iterator is
+// exhausted.
+func (it *MediaItemIterator) Next() (*photoslibrary.MediaItem, error) {
+	for len(it.items) == 0 {
+		if it.done {
+			return nil, ErrDone
+		}
+
+		items, nextPageToken, err := it.fetch(it.pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		it.items = items
+		it.pageToken = nextPageToken
+		if it.pageToken == "" {
+			it.done = true
+		}
+	}
+
+	item := it.items[0]
+	it.items = it.items[1:]
+	return item, nil
+}
+
+// ListMediaItems returns an iterator over every media item in the
+// library.
+func (u *Uploader) ListMediaItems(ctx context.Context) *MediaItemIterator {
+	return &MediaItemIterator{
+		ctx: ctx,
+		fetch: func(pageToken string) ([]*photoslibrary.MediaItem, string, error) {
+			call := u.service.MediaItems.List().Context(ctx).PageSize(100)
+			if pageToken != "" {
+				call = call.PageToken(pageToken)
+			}
+
+			resp, err := call.Do()
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.MediaItems, resp.NextPageToken, nil
+		},
+	}
+}
+
+// GetMediaItem fetches a single media item by ID.
+func (u *Uploader) GetMediaItem(ctx context.Context, id string) (*photoslibrary.MediaItem, error) {
+	return u.service.MediaItems.Get(id).Context(ctx).Do()
+}
+
+// SearchRequest describes a mediaItems.search query. Filters follows the
+// shape of the Library API's Filters message: date ranges, content
+// categories, media type, favorites, and whether to include archived
+// items.
+type SearchRequest struct {
+	AlbumID AlbumID
+	Filters *photoslibrary.Filters
+}
+
+// SearchMediaItems returns an iterator over the media items matching
+// req.
+func (u *Uploader) SearchMediaItems(ctx context.Context, req SearchRequest) *MediaItemIterator {
+	return &MediaItemIterator{
+		ctx: ctx,
+		fetch: func(pageToken string) ([]*photoslibrary.MediaItem, string, error) {
+			apiReq := &photoslibrary.SearchMediaItemsRequest{
+				AlbumId:   string(req.AlbumID),
+				Filters:   req.Filters,
+				PageSize:  100,
+				PageToken: pageToken,
+			}
+
+			resp, err := u.service.MediaItems.Search(apiReq).Context(ctx).Do()
+			if err != nil {
+				return nil, "", err
+			}
+			return resp.MediaItems, resp.NextPageToken, nil
+		},
+	}
+}
+
+// downloadError carries the HTTP status code of a failed download so
+// Download can tell a stale baseUrl (403) apart from other failures.
+type downloadError struct {
+	statusCode int
+}
+
+func (e *downloadError) Error() string {
+	return fmt.Sprintf("gphotos: download failed with status %d", e.statusCode)
+}
+
+// Download writes the full-resolution bytes of item to w, using "=d"
+// for photos or "=dv" for videos as the Library API requires. A
+// mediaItem's BaseUrl expires after about an hour; if the download
+// fails with 403, Download re-fetches the item once via GetMediaItem
+// and retries with the refreshed BaseUrl.
+func (u *Uploader) Download(ctx context.Context, item *photoslibrary.MediaItem, w io.Writer) error {
+	err := u.download(ctx, item, w)
+
+	var dlErr *downloadError
+	if errors.As(err, &dlErr) && dlErr.statusCode == http.StatusForbidden {
+		fresh, getErr := u.GetMediaItem(ctx, item.Id)
+		if getErr != nil {
+			return err
+		}
+		return u.download(ctx, fresh, w)
+	}
+
+	return err
+}
+
+func (u *Uploader) download(ctx context.Context, item *photoslibrary.MediaItem, w io.Writer) error {
+	url := item.BaseUrl + "=d"
+	if item.MediaMetadata != nil && item.MediaMetadata.Video != nil {
+		url = item.BaseUrl + "=dv"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &downloadError{statusCode: resp.StatusCode}
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,27 @@
+package gphotos
+
+// Option configures an Uploader constructed by NewUploader.
+type Option func(*Uploader)
+
+// WithPacer overrides the default rate limiter used to serialize
+// CreateMediaItems calls.
+func WithPacer(p *Pacer) Option {
+	return func(u *Uploader) { u.pacer = p }
+}
+
+// WithRetry overrides the default policy used to decide whether and how
+// long to wait before retrying a failed CreateMediaItems call.
+func WithRetry(policy RetryPolicy) Option {
+	return func(u *Uploader) { u.retryPolicy = policy }
+}
+
+// WithProgress registers p to receive upload lifecycle events. Passing a
+// nil p is a no-op, so callers can wire an optional progress flag
+// straight through without a conditional.
+func WithProgress(p Progress) Option {
+	return func(u *Uploader) {
+		if p != nil {
+			u.progress = p
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package gphotos
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pacer is a token-bucket rate limiter used to serialize CreateMediaItems
+// calls so a long-running sync spreads its writes out against Google
+// Photos' write quota (10k requests/day).
+type Pacer struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64 // tokens added per second
+	last   time.Time
+}
+
+// NewPacer returns a Pacer that allows qps requests per second on
+// average, with bursts of up to burst requests before it starts
+// blocking.
+func NewPacer(qps float64, burst int) *Pacer {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Pacer{
+		tokens: float64(burst),
+		max:    float64(burst),
+		rate:   qps,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (p *Pacer) Wait(ctx context.Context) error {
+	for {
+		wait := p.reserve()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve refills the bucket, consumes a token if one is available, and
+// returns how long the caller should wait before trying again.
+func (p *Pacer) reserve() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.tokens += now.Sub(p.last).Seconds() * p.rate
+	if p.tokens > p.max {
+		p.tokens = p.max
+	}
+	p.last = now
+
+	if p.tokens >= 1 {
+		p.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - p.tokens) / p.rate * float64(time.Second))
+}
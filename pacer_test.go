@@ -0,0 +1,135 @@
+package gphotos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewPacer(t *testing.T) {
+	cases := []struct {
+		name     string
+		qps      float64
+		burst    int
+		wantMax  float64
+		wantInit float64
+	}{
+		{name: "normal burst", qps: 10, burst: 10, wantMax: 10, wantInit: 10},
+		{name: "burst clamped to 1", qps: 5, burst: 0, wantMax: 1, wantInit: 1},
+		{name: "negative burst clamped to 1", qps: 5, burst: -3, wantMax: 1, wantInit: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPacer(tc.qps, tc.burst)
+			if p.max != tc.wantMax {
+				t.Errorf("max = %v, want %v", p.max, tc.wantMax)
+			}
+			if p.tokens != tc.wantInit {
+				t.Errorf("tokens = %v, want %v", p.tokens, tc.wantInit)
+			}
+			if p.rate != tc.qps {
+				t.Errorf("rate = %v, want %v", p.rate, tc.qps)
+			}
+		})
+	}
+}
+
+func TestPacerReserve(t *testing.T) {
+	cases := []struct {
+		name        string
+		tokens      float64
+		max         float64
+		rate        float64
+		elapsed     time.Duration
+		wantWaitMax time.Duration
+		wantNoWait  bool
+	}{
+		{
+			name:       "token already available",
+			tokens:     1,
+			max:        10,
+			rate:       10,
+			elapsed:    0,
+			wantNoWait: true,
+		},
+		{
+			name:        "bucket empty, must wait roughly one token's worth",
+			tokens:      0,
+			max:         10,
+			rate:        10, // one token per 100ms
+			elapsed:     0,
+			wantWaitMax: 100 * time.Millisecond,
+		},
+		{
+			name:       "refill fills in a token",
+			tokens:     0,
+			max:        10,
+			rate:       10,
+			elapsed:    200 * time.Millisecond,
+			wantNoWait: true,
+		},
+		{
+			name:       "large refill grants a token",
+			tokens:     9,
+			max:        10,
+			rate:       1000,
+			elapsed:    time.Second,
+			wantNoWait: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Pacer{
+				tokens: tc.tokens,
+				max:    tc.max,
+				rate:   tc.rate,
+				last:   time.Now().Add(-tc.elapsed),
+			}
+
+			wait := p.reserve()
+			if tc.wantNoWait {
+				if wait != 0 {
+					t.Errorf("reserve() wait = %v, want 0", wait)
+				}
+				return
+			}
+
+			if wait <= 0 || wait > tc.wantWaitMax {
+				t.Errorf("reserve() wait = %v, want (0, %v]", wait, tc.wantWaitMax)
+			}
+		})
+	}
+}
+
+func TestPacerReserveClampsToMax(t *testing.T) {
+	p := &Pacer{tokens: 1, max: 1, rate: 100, last: time.Now().Add(-time.Hour)}
+
+	p.reserve()
+	if p.tokens > p.max {
+		t.Errorf("tokens = %v, exceeded max %v after a long idle period", p.tokens, p.max)
+	}
+}
+
+func TestPacerWaitRespectsContext(t *testing.T) {
+	p := &Pacer{tokens: 0, max: 1, rate: 0.001, last: time.Now()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := p.Wait(ctx); err != ctx.Err() {
+		t.Errorf("Wait() err = %v, want %v", err, ctx.Err())
+	}
+}
+
+func TestPacerWaitReturnsOnceTokenAvailable(t *testing.T) {
+	p := NewPacer(1000, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := p.Wait(ctx); err != nil {
+		t.Fatalf("Wait() err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,298 @@
+package gphotos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultChunkSize is the chunk size UploadResumable uses when the
+// Uploader's ChunkSize field is left unset.
+const DefaultChunkSize = 8 * 1024 * 1024 // 8 MiB
+
+// resumeSession is the on-disk journal entry for an in-progress
+// resumable upload. It lets an aborted run recover the session URL and
+// continue from the last accepted offset instead of starting over.
+type resumeSession struct {
+	URL      string `json:"url"`
+	Offset   int64  `json:"offset"`
+	FileName string `json:"file_name"`
+	Size     int64  `json:"size"`
+}
+
+func resumeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gphotos", "resume"), nil
+}
+
+func resumeKey(filename string, size int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", filename, size)))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadResumeSession(filename string, size int64) (*resumeSession, error) {
+	dir, err := resumeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, resumeKey(filename, size)+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	session := new(resumeSession)
+	if err := json.NewDecoder(f).Decode(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func saveResumeSession(session *resumeSession) error {
+	dir, err := resumeDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, resumeKey(session.FileName, session.Size)+".json"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(session)
+}
+
+func removeResumeSession(filename string, size int64) error {
+	dir, err := resumeDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, resumeKey(filename, size)+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// UploadResumable uploads r using Google's resumable upload protocol
+// instead of the single-request raw protocol used by Upload. It is
+// intended for large files on flaky networks: progress is journaled to
+// ~/.gphotos/resume so that a process that is killed mid-upload can
+// continue from the last chunk the server acknowledged instead of
+// re-sending the whole file.
+func (u *Uploader) UploadResumable(ctx context.Context, filename string, r io.Reader, size int64) (token UploadToken, err error) {
+	u.progress.OnFileStart(filename, size)
+	defer func() { u.progress.OnFileDone(filename, token, err) }()
+
+	chunkSize := u.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	session, err := loadResumeSession(filename, size)
+	if err != nil {
+		return "", err
+	}
+
+	var granularity int64
+	if session != nil {
+		offset, gran, err := u.queryUploadStatus(ctx, session.URL)
+		if err != nil {
+			return "", err
+		}
+		session.Offset = offset
+		granularity = gran
+	} else {
+		url, gran, err := u.startResumableSession(ctx, filename, size)
+		if err != nil {
+			return "", err
+		}
+
+		session = &resumeSession{URL: url, FileName: filename, Size: size}
+		granularity = gran
+		if err := saveResumeSession(session); err != nil {
+			return "", err
+		}
+	}
+
+	if granularity <= 0 {
+		granularity = chunkSize
+	}
+	if chunkSize < granularity {
+		chunkSize = granularity
+	} else {
+		chunkSize -= chunkSize % granularity
+	}
+
+	if session.Offset > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r, session.Offset); err != nil {
+			return "", fmt.Errorf("gphotos: could not seek to resume offset %d: %w", session.Offset, err)
+		}
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return "", err
+		}
+
+		offset := session.Offset
+		session.Offset += int64(n)
+		finalize := session.Offset >= size
+
+		if !finalize && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			return "", fmt.Errorf("gphotos: reader for %q ended after %d bytes, want size %d", filename, session.Offset, size)
+		}
+
+		chunkToken, err := u.uploadChunk(ctx, session.URL, buf[:n], offset, finalize)
+		if err != nil {
+			return "", err
+		}
+		u.progress.OnFileBytes(filename, int64(n))
+
+		if finalize {
+			return chunkToken, removeResumeSession(filename, size)
+		}
+
+		if err := saveResumeSession(session); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (u *Uploader) startResumableSession(ctx context.Context, filename string, size int64) (url string, granularity int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://photoslibrary.googleapis.com/v1/uploads", nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	req.Header.Set("content-length", "0")
+	req.Header.Set("x-goog-upload-command", "start")
+	req.Header.Set("x-goog-upload-protocol", "resumable")
+	req.Header.Set("x-goog-upload-content-type", "application/octet-stream")
+	req.Header.Set("x-goog-upload-raw-size", strconv.FormatInt(size, 10))
+	req.Header.Set("x-goog-upload-file-name", filename)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("gphotos: resumable upload session start failed with status %d: %s", resp.StatusCode, readBodySnippet(resp.Body))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	url = resp.Header.Get("x-goog-upload-url")
+	if url == "" {
+		return "", 0, fmt.Errorf("gphotos: resumable upload session response did not contain an x-goog-upload-url header")
+	}
+
+	granularity, _ = strconv.ParseInt(resp.Header.Get("x-goog-upload-chunk-granularity"), 10, 64)
+	return url, granularity, nil
+}
+
+func (u *Uploader) queryUploadStatus(ctx context.Context, uploadURL string) (offset int64, granularity int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("x-goog-upload-command", "query")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("gphotos: resumable upload status query failed with status %d: %s", resp.StatusCode, readBodySnippet(resp.Body))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	offset, err = strconv.ParseInt(resp.Header.Get("x-goog-upload-size-received"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gphotos: could not parse x-goog-upload-size-received from query response: %w", err)
+	}
+
+	granularity, _ = strconv.ParseInt(resp.Header.Get("x-goog-upload-chunk-granularity"), 10, 64)
+	return offset, granularity, nil
+}
+
+func (u *Uploader) uploadChunk(ctx context.Context, uploadURL string, chunk []byte, offset int64, finalize bool) (UploadToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, bytes.NewReader(chunk))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(chunk))
+
+	req.Header.Set("x-goog-upload-offset", strconv.FormatInt(offset, 10))
+	if finalize {
+		req.Header.Set("x-goog-upload-command", "upload, finalize")
+	} else {
+		req.Header.Set("x-goog-upload-command", "upload")
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gphotos: resumable upload chunk at offset %d failed with status %d: %s", offset, resp.StatusCode, bodySnippet(body))
+	}
+
+	if !finalize {
+		return "", nil
+	}
+	return UploadToken(body), nil
+}
+
+// maxBodySnippet caps how much of an error response body is echoed back
+// in an error message, so a misbehaving proxy returning an HTML error
+// page doesn't dump megabytes of markup into the caller's logs.
+const maxBodySnippet = 512
+
+// bodySnippet trims body to maxBodySnippet bytes for inclusion in an
+// error message.
+func bodySnippet(body []byte) string {
+	if len(body) > maxBodySnippet {
+		body = body[:maxBodySnippet]
+	}
+	return string(body)
+}
+
+// readBodySnippet is like bodySnippet but reads r itself, for callers
+// that have not already buffered the response body.
+func readBodySnippet(r io.Reader) string {
+	body, _ := ioutil.ReadAll(io.LimitReader(r, maxBodySnippet))
+	return string(body)
+}
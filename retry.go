@@ -0,0 +1,125 @@
+package gphotos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy decides whether an error returned from the Library API is
+// worth retrying and how long to wait before the next attempt.
+type RetryPolicy interface {
+	// Retry reports whether attempt (1-indexed, the attempt that just
+	// failed with err) should be retried, and if so, how long to wait
+	// before trying again.
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+// DefaultRetryPolicy retries 429s, 5xxs, and transient network errors
+// with exponential backoff, honoring any Retry-After header the server
+// sends. Other 4xx errors fail fast.
+type DefaultRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NewDefaultRetryPolicy returns a DefaultRetryPolicy with sensible
+// defaults: 5 attempts, starting at 500ms and capped at 30s.
+func NewDefaultRetryPolicy() *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+func (p *DefaultRetryPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Code != http.StatusTooManyRequests && apiErr.Code < 500 {
+			return 0, false
+		}
+		if d, ok := retryAfter(apiErr.Header); ok {
+			return d, true
+		}
+		return p.backoff(attempt), true
+	}
+
+	if isTransient(err) {
+		return p.backoff(attempt), true
+	}
+
+	return 0, false
+}
+
+func (p *DefaultRetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func retryAfter(header http.Header) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
+
+// Retry calls fn, retrying according to policy until it succeeds, fails
+// permanently, or ctx is done. fn must be safe to call more than once;
+// the generated Library API service calls are (they re-marshal their
+// request on every Do()). If policy is nil, NewDefaultRetryPolicy is
+// used.
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	if policy == nil {
+		policy = NewDefaultRetryPolicy()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		wait, retry := policy.Retry(attempt, err)
+		if !retry {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package gphotos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+type fakeNetError struct {
+	timeout   bool
+	temporary bool
+}
+
+func (e *fakeNetError) Error() string   { return "fake net error" }
+func (e *fakeNetError) Timeout() bool   { return e.timeout }
+func (e *fakeNetError) Temporary() bool { return e.temporary }
+
+func TestDefaultRetryPolicyRetry(t *testing.T) {
+	policy := &DefaultRetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	cases := []struct {
+		name      string
+		attempt   int
+		err       error
+		wantRetry bool
+	}{
+		{name: "max attempts reached", attempt: 3, err: &googleapi.Error{Code: 500}, wantRetry: false},
+		{name: "429 is retried", attempt: 1, err: &googleapi.Error{Code: http.StatusTooManyRequests}, wantRetry: true},
+		{name: "5xx is retried", attempt: 1, err: &googleapi.Error{Code: 503}, wantRetry: true},
+		{name: "other 4xx fails fast", attempt: 1, err: &googleapi.Error{Code: 404}, wantRetry: false},
+		{name: "transient network error is retried", attempt: 1, err: &fakeNetError{timeout: true}, wantRetry: true},
+		{name: "non-transient network error is not retried", attempt: 1, err: &fakeNetError{}, wantRetry: false},
+		{name: "unrecognized error is not retried", attempt: 1, err: errors.New("boom"), wantRetry: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, retry := policy.Retry(tc.attempt, tc.err)
+			if retry != tc.wantRetry {
+				t.Errorf("Retry(%d, %v) retry = %v, want %v", tc.attempt, tc.err, retry, tc.wantRetry)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicyBackoff(t *testing.T) {
+	policy := &DefaultRetryPolicy{MaxAttempts: 10, BaseDelay: 500 * time.Millisecond, MaxDelay: 4 * time.Second}
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 500 * time.Millisecond},
+		{attempt: 2, want: time.Second},
+		{attempt: 3, want: 2 * time.Second},
+		{attempt: 4, want: 4 * time.Second},  // 500ms<<3, exactly MaxDelay
+		{attempt: 10, want: 4 * time.Second}, // would be 256s, capped at MaxDelay
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("attempt=%d", tc.attempt), func(t *testing.T) {
+			if got := policy.backoff(tc.attempt); got != tc.want {
+				t.Errorf("backoff(%d) = %v, want %v", tc.attempt, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		wantOK bool
+		want   time.Duration
+	}{
+		{name: "absent", header: "", wantOK: false},
+		{name: "seconds", header: "30", wantOK: true, want: 30 * time.Second},
+		{name: "http-date", header: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), wantOK: true, want: time.Minute},
+		{name: "garbage", header: "not-a-date", wantOK: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			if tc.header != "" {
+				h.Set("Retry-After", tc.header)
+			}
+
+			d, ok := retryAfter(h)
+			if ok != tc.wantOK {
+				t.Fatalf("retryAfter() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			// http-date retry-after is computed relative to time.Now(), so
+			// allow a little slack.
+			if d < tc.want-5*time.Second || d > tc.want+5*time.Second {
+				t.Errorf("retryAfter() = %v, want ~%v", d, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicyHonorsRetryAfterHeader(t *testing.T) {
+	policy := NewDefaultRetryPolicy()
+	err := &googleapi.Error{
+		Code:   http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{"7"}},
+	}
+
+	wait, retry := policy.Retry(1, err)
+	if !retry {
+		t.Fatalf("Retry() retry = false, want true")
+	}
+	if wait != 7*time.Second {
+		t.Errorf("Retry() wait = %v, want 7s", wait)
+	}
+}
+
+func TestRetrySucceedsAfterTransientFailures(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), &DefaultRetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, func() error {
+		attempts++
+		if attempts < 3 {
+			return &fakeNetError{timeout: true}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Retry() err = %v, want nil", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestRetryGivesUpOnPermanentError(t *testing.T) {
+	attempts := 0
+	wantErr := &googleapi.Error{Code: 404}
+	err := Retry(context.Background(), &DefaultRetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, func() error {
+		attempts++
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Fatalf("Retry() err = %v, want %v", err, wantErr)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (no retry on permanent error)", attempts)
+	}
+}
+
+func TestRetryRespectsContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := Retry(ctx, &DefaultRetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}, func() error {
+		attempts++
+		cancel()
+		return &fakeNetError{timeout: true}
+	})
+
+	if err != context.Canceled {
+		t.Fatalf("Retry() err = %v, want context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRetryDefaultsPolicyWhenNil(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), nil, func() error {
+		attempts++
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Retry() err = %v, want nil", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
@@ -1,6 +1,7 @@
 package gphotos
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -19,20 +20,63 @@ type Uploader struct {
 	client *http.Client
 
 	service *photoslibrary.Service
+
+	// ChunkSize controls the chunk size UploadResumable uses, rounded down
+	// to a multiple of the server's chunk granularity (or raised to one
+	// granularity if smaller). If zero, DefaultChunkSize is used.
+	ChunkSize int64
+
+	pacer       *Pacer
+	retryPolicy RetryPolicy
+	progress    Progress
 }
 
-func NewUploader(client *http.Client) *Uploader {
+// NewUploader constructs an Uploader that issues Library API requests
+// using client. By default, CreateMediaItems calls are paced to 10 QPS
+// and retried with NewDefaultRetryPolicy, and progress events are
+// discarded; pass WithPacer, WithRetry, and/or WithProgress to override
+// any of these.
+func NewUploader(client *http.Client, opts ...Option) *Uploader {
 	// Only reason for error is if client is nil
 	service, _ := photoslibrary.New(client)
 
-	return &Uploader{
-		client:  client,
-		service: service,
+	u := &Uploader{
+		client:      client,
+		service:     service,
+		pacer:       NewPacer(10, 10),
+		retryPolicy: NewDefaultRetryPolicy(),
+		progress:    noopProgress{},
 	}
+
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	return u
 }
 
-func (u *Uploader) Upload(filename string, r io.Reader) (UploadToken, error) {
-	req, err := http.NewRequest("POST", "https://photoslibrary.googleapis.com/v1/uploads", r)
+// RetryPolicy returns the policy this Uploader was configured with (via
+// WithRetry, or NewDefaultRetryPolicy if that option was not passed), so
+// callers that drive their own retry loop around Upload/UploadResumable
+// can reuse the same policy instead of constructing a disconnected one.
+func (u *Uploader) RetryPolicy() RetryPolicy {
+	return u.retryPolicy
+}
+
+// Upload sends r to Google Photos in a single request using the raw
+// upload protocol. It does not retry internally, since r may not be
+// safe to read more than once; callers that need retries on flaky
+// networks should either retry at a higher level (reopening the file
+// each attempt) or use UploadResumable, which journals its progress.
+func (u *Uploader) Upload(ctx context.Context, filename string, r io.Reader) (UploadToken, error) {
+	u.progress.OnFileStart(filename, -1)
+	token, err := u.upload(ctx, filename, &progressReader{r: r, filename: filename, progress: u.progress})
+	u.progress.OnFileDone(filename, token, err)
+	return token, err
+}
+
+func (u *Uploader) upload(ctx context.Context, filename string, r io.Reader) (UploadToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://photoslibrary.googleapis.com/v1/uploads", r)
 	if err != nil {
 		return "", err
 	}
@@ -55,28 +99,76 @@ func (u *Uploader) Upload(filename string, r io.Reader) (UploadToken, error) {
 	return UploadToken(rawToken), nil
 }
 
-func (u *Uploader) CreateMediaItems(tokens []UploadToken) error {
-	if len(tokens) > MaxUploadTokensPerCreateMediaItemsCall {
-		return fmt.Errorf("too many tokens, got %v, cannot handle more than %v", len(tokens), MaxUploadTokensPerCreateMediaItemsCall)
+// MediaItem pairs an UploadToken with the per-item metadata that
+// CreateMediaItemsWithMetadata can attach when the item is created: a description
+// and/or a filename that overrides the one the file was uploaded under.
+type MediaItem struct {
+	Token       UploadToken
+	Description string
+	FileName    string
+}
+
+// CreateMediaItemsOptions controls how CreateMediaItems files newly
+// created media items into an album.
+type CreateMediaItemsOptions struct {
+	// AlbumID, if set, adds the new media items to the named album.
+	AlbumID AlbumID
+	// AlbumPosition controls where in AlbumID the items are placed. It
+	// is ignored if AlbumID is empty.
+	AlbumPosition AlbumPosition
+}
+
+func (u *Uploader) CreateMediaItems(ctx context.Context, tokens []UploadToken, opts CreateMediaItemsOptions) error {
+	items := make([]MediaItem, len(tokens))
+	for i, token := range tokens {
+		items[i] = MediaItem{Token: token}
 	}
 
-	newMediaItems := make([]*photoslibrary.NewMediaItem, 0, len(tokens))
-	for _, token := range tokens {
+	_, err := u.CreateMediaItemsWithMetadata(ctx, items, opts)
+	return err
+}
+
+// CreateMediaItemsWithMetadata is like CreateMediaItems but allows a
+// description and/or filename override to be attached to each item. It
+// returns one result per item, in the same order, so callers can learn
+// the created media item IDs (e.g. to populate a Deduper).
+func (u *Uploader) CreateMediaItemsWithMetadata(ctx context.Context, items []MediaItem, opts CreateMediaItemsOptions) ([]*photoslibrary.NewMediaItemResult, error) {
+	if len(items) > MaxUploadTokensPerCreateMediaItemsCall {
+		return nil, fmt.Errorf("too many tokens, got %v, cannot handle more than %v", len(items), MaxUploadTokensPerCreateMediaItemsCall)
+	}
+
+	newMediaItems := make([]*photoslibrary.NewMediaItem, 0, len(items))
+	for _, item := range items {
 		newMediaItems = append(newMediaItems, &photoslibrary.NewMediaItem{
+			Description: item.Description,
 			SimpleMediaItem: &photoslibrary.SimpleMediaItem{
-				UploadToken: string(token),
+				UploadToken: string(item.Token),
+				FileName:    item.FileName,
 			},
 		})
 	}
 
 	req := &photoslibrary.BatchCreateMediaItemsRequest{
 		NewMediaItems: newMediaItems,
+		AlbumId:       string(opts.AlbumID),
+	}
+	if position := opts.AlbumPosition.apiValue(); position != "" && opts.AlbumID != "" {
+		req.AlbumPosition = &photoslibrary.AlbumPosition{Position: position}
 	}
 
-	_, err := u.service.MediaItems.BatchCreate(req).Do()
-	if err != nil {
-		return err
+	if err := u.pacer.Wait(ctx); err != nil {
+		return nil, err
 	}
 
-	return nil
+	var results []*photoslibrary.NewMediaItemResult
+	err := Retry(ctx, u.retryPolicy, func() error {
+		resp, err := u.service.MediaItems.BatchCreate(req).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		results = resp.NewMediaItemResults
+		return nil
+	})
+	u.progress.OnBatchDone(len(items), err)
+	return results, err
 }